@@ -0,0 +1,51 @@
+//go:build bench
+
+// Tests for the benchmark helpers. Built only with -tags bench (see `make
+// bench`) so they stay out of the student test run.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                            Helper Tests                                    */
+/******************************************************************************/
+
+func TestPercentile(t *testing.T) {
+	ms := func(ns ...int) []time.Duration {
+		d := make([]time.Duration, len(ns))
+		for i, n := range ns {
+			d[i] = time.Duration(n) * time.Millisecond
+		}
+		return d
+	}
+	twelve := ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+
+	cases := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   float64
+	}{
+		{"Empty", nil, 99, 0},
+		{"One&p50", ms(7), 50, 7},
+		{"One&p99", ms(7), 99, 7},
+		{"Four&p50", ms(1, 2, 3, 4), 50, 2},
+		{"Four&p99", ms(1, 2, 3, 4), 99, 4},
+		{"Twelve&p50", twelve, 50, 6},
+		{"Twelve&p99", twelve, 99, 12},
+		{"Twelve&p0", twelve, 0, 1},
+		{"Twelve&p100", twelve, 100, 12},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := percentile(c.sorted, c.p); got != c.want {
+				t.Errorf("percentile(%v, %v) = %v, want %v", c.sorted, c.p, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+/******************************************************************************/
+/*                           Benchmark Parameters                             */
+/******************************************************************************/
+
+// How long a single round of transfers may take before the benchmark gives up.
+// Clients still running when it expires are killed.
+const BenchTimeout = 30 * time.Second
+
+// Payload shape (rows x cols, as passed to randString())
+type benchPayload struct {
+	rows, cols int
+}
+
+var (
+	benchClients = flag.Int("bench.clients", 4, "number of concurrent clients per round")
+	benchSizes   = flag.String("bench.sizes", "1x63,64x512,2048x512", "comma-separated payload shapes (rows x cols, as passed to randString())")
+	benchJSON    = flag.String("bench.json", "", "write benchmark results as JSON lines to this file")
+)
+
+/******************************************************************************/
+/*                           Benchmark Results                                */
+/******************************************************************************/
+
+// BenchResult holds the measurements of one sub-benchmark
+type BenchResult struct {
+	Name          string   `json:"name"`
+	Clients       int      `json:"clients"`
+	PayloadBytes  int      `json:"payload_bytes"`
+	Printable     bool     `json:"printable"`
+	Rounds        int      `json:"rounds"`
+	MBPerSec      float64  `json:"mb_per_sec"`
+	P50Ms         float64  `json:"p50_ms"`
+	P99Ms         float64  `json:"p99_ms"`
+	ClientRSSKB   int64    `json:"client_max_rss_kb"`                // largest peak RSS of any client
+	ServerRSSKB   *int64   `json:"server_max_rss_kb,omitempty"`      // nil if /proc is unavailable
+	SyscallsPerMB *float64 `json:"server_syscalls_per_mb,omitempty"` // nil if unavailable or not counted
+}
+
+// Results keyed by name. Go runs each benchmark several times with growing b.N,
+// so only the last (longest) run of each is kept.
+var (
+	benchResults     = make(map[string]BenchResult)
+	benchResultNames []string
+	benchResultsMu   sync.Mutex
+)
+
+func recordBenchResult(res BenchResult) {
+	benchResultsMu.Lock()
+	defer benchResultsMu.Unlock()
+	if _, ok := benchResults[res.Name]; !ok {
+		benchResultNames = append(benchResultNames, res.Name)
+	}
+	benchResults[res.Name] = res
+}
+
+// writeBenchResults writes all recorded results to the -bench.json file, if set
+func writeBenchResults() {
+	if *benchJSON == "" || len(benchResultNames) == 0 {
+		return
+	}
+
+	file, err := os.Create(*benchJSON)
+	if err != nil {
+		log.Println("Failed to create benchmark results file:", err)
+		return
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	for _, name := range benchResultNames {
+		if err := enc.Encode(benchResults[name]); err != nil {
+			log.Println("Failed to write benchmark results:", err)
+			return
+		}
+	}
+}
+
+/******************************************************************************/
+/*                              Helper Functions                              */
+/******************************************************************************/
+
+// parsePayloads parses a comma-separated list of "ROWSxCOLS" payload shapes
+func parsePayloads(s string) ([]benchPayload, error) {
+	payloads := make([]benchPayload, 0)
+	for _, shape := range strings.Split(s, ",") {
+		var p benchPayload
+		rows, cols, found := strings.Cut(strings.TrimSpace(shape), "x")
+		if !found {
+			return nil, fmt.Errorf("bad payload shape %q: expected ROWSxCOLS", shape)
+		}
+		var err error
+		if p.rows, err = strconv.Atoi(rows); err != nil || p.rows <= 0 {
+			return nil, fmt.Errorf("bad payload shape %q: rows must be a positive integer", shape)
+		}
+		if p.cols, err = strconv.Atoi(cols); err != nil || p.cols <= 0 {
+			return nil, fmt.Errorf("bad payload shape %q: cols must be a positive integer", shape)
+		}
+		payloads = append(payloads, p)
+	}
+	return payloads, nil
+}
+
+// percentile returns the p-th percentile (0-100) of sorted durations, in ms,
+// using the nearest-rank method (so p99 of fewer than 100 samples is the max)
+func percentile(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	} else if i > len(sorted)-1 {
+		i = len(sorted) - 1
+	}
+	return float64(sorted[i]) / float64(time.Millisecond)
+}
+
+// procSyscalls returns the number of read and write syscalls made so far by
+// process pid, as reported by /proc/[pid]/io. ok is false if unavailable.
+// Note that splice(2) and sendfile(2) are not counted.
+func procSyscalls(pid int) (n int64, ok bool) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/io", pid))
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || (fields[0] != "syscr:" && fields[0] != "syscw:") {
+			continue
+		}
+		v, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		n += v
+	}
+	return n, scanner.Err() == nil
+}
+
+// procMaxRSS returns the peak resident set size of process pid in KB, as
+// reported by VmHWM in /proc/[pid]/status. ok is false if unavailable.
+func procMaxRSS(pid int) (kb int64, ok bool) {
+	file, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "VmHWM:" && fields[2] == "kB" {
+			kb, err = strconv.ParseInt(fields[1], 10, 64)
+			return kb, err == nil
+		}
+	}
+	return 0, false
+}
+
+// exitedMaxRSS returns the peak resident set size in KB of an exited process
+func exitedMaxRSS(ps *os.ProcessState) int64 {
+	rusage, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	if runtime.GOOS == "darwin" {
+		return int64(rusage.Maxrss) / 1024 // bytes on macOS
+	}
+	return int64(rusage.Maxrss)
+}
+
+// runClient runs the student client, sending msg to the server on port. The
+// client is killed if ctx expires first. Returns how long the client took to
+// send everything and exit, and its peak RSS in KB.
+func runClient(ctx context.Context, port, msg string) (time.Duration, int64, error) {
+	client_exe := filepath.Join(solutionDir, "client")
+	cmd := exec.CommandContext(ctx, client_exe, "127.0.0.1", port)
+	cmd.Stdin = strings.NewReader(msg)
+	cmd.Stderr = os.Stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	d := time.Since(start)
+	if ctx.Err() != nil {
+		return d, 0, fmt.Errorf("killed after %s: %s", BenchTimeout, ctx.Err())
+	} else if err != nil {
+		return d, 0, err
+	}
+	return d, exitedMaxRSS(cmd.ProcessState), nil
+}
+
+// checkRound verifies that one round of server output consists of exactly
+// `clients` whole copies of msg. The server handles clients one at a time, so
+// each client's message must appear contiguously.
+func checkRound(output, msg []byte, clients int) error {
+	if len(output) != clients*len(msg) {
+		return fmt.Errorf("expected %d bytes of output, got %d", clients*len(msg), len(output))
+	}
+	for c := 0; c < clients; c++ {
+		chunk := output[c*len(msg) : (c+1)*len(msg)]
+		if !bytes.Equal(chunk, msg) {
+			return fmt.Errorf(testFailMessage, Message{string(msg)}, Message{string(chunk)})
+		}
+	}
+	return nil
+}
+
+// benchTransfer starts one student server and runs b.N rounds against it, each
+// of `clients` concurrent clients sending msg. Reports throughput and latency.
+func benchTransfer(b *testing.B, clients int, msg string, printable bool) {
+	srv := NewServer(DefaultPort)
+	if err := srv.Start(b); err != nil {
+		b.Fatal(err)
+	}
+	defer srv.Stop(b)
+
+	roundBytes := clients * len(msg)
+	buf := make([]byte, roundBytes)
+	latencies := make([]time.Duration, 0, b.N*clients)
+	var clientRSS int64
+
+	pid := srv.cmd.Process.Pid
+	syscallsBefore, haveSyscalls := procSyscalls(pid)
+	bmsg := []byte(msg)
+
+	b.SetBytes(int64(roundBytes))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// The whole round, clients included, must finish within BenchTimeout
+		ctx, cancel := context.WithTimeout(context.Background(), BenchTimeout)
+
+		// Drain the server's stdout concurrently so it never blocks on the pipe
+		readErr := make(chan error, 1)
+		go func() {
+			_, err := io.ReadFull(srv.stdout, buf)
+			readErr <- err
+		}()
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		clientErrs := make([]string, 0)
+		for c := 0; c < clients; c++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d, rss, err := runClient(ctx, srv.port, msg)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					clientErrs = append(clientErrs, err.Error())
+					return
+				}
+				latencies = append(latencies, d)
+				clientRSS = max(clientRSS, rss)
+			}()
+		}
+		wg.Wait()
+
+		// No point waiting for output that a failed client will never send
+		if len(clientErrs) != 0 {
+			cancel()
+			b.Fatalf("Round %d: client failed: %s", i+1, strings.Join(clientErrs, "; "))
+		}
+
+		select {
+		case err := <-readErr:
+			cancel()
+			if err != nil {
+				b.Fatalf("Failed to read server output: %s", err)
+			}
+			// Don't report numbers for a broken transfer
+			if err := checkRound(buf, bmsg, clients); err != nil {
+				b.Fatalf("Round %d: %s", i+1, err)
+			}
+		case <-ctx.Done():
+			cancel()
+			b.Fatalf("Timed out waiting for %d bytes of server output", roundBytes)
+		}
+	}
+
+	b.StopTimer()
+	elapsed := b.Elapsed()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	mb := float64(roundBytes*b.N) / 1e6
+	res := BenchResult{
+		Name:         b.Name(),
+		Clients:      clients,
+		PayloadBytes: len(msg),
+		Printable:    printable,
+		Rounds:       b.N,
+		MBPerSec:     mb / elapsed.Seconds(),
+		P50Ms:        percentile(latencies, 50),
+		P99Ms:        percentile(latencies, 99),
+		ClientRSSKB:  clientRSS,
+	}
+	if rss, ok := procMaxRSS(pid); ok {
+		res.ServerRSSKB = &rss
+		b.ReportMetric(float64(rss), "server-rss-kB")
+	}
+	// A zero delta means the server copied with splice(2), which isn't counted
+	if syscallsAfter, ok := procSyscalls(pid); ok && haveSyscalls && syscallsAfter > syscallsBefore {
+		perMB := float64(syscallsAfter-syscallsBefore) / mb
+		res.SyscallsPerMB = &perMB
+		b.ReportMetric(perMB, "syscalls/MB")
+	}
+	b.ReportMetric(float64(res.ClientRSSKB), "client-rss-kB")
+	b.ReportMetric(res.P50Ms, "p50-ms")
+	b.ReportMetric(res.P99Ms, "p99-ms")
+
+	recordBenchResult(res)
+}
+
+/******************************************************************************/
+/*                               Benchmarks                                   */
+/******************************************************************************/
+
+// Student Client ⇌ Student Server
+func BenchmarkTransfer(b *testing.B) {
+	payloads, err := parsePayloads(*benchSizes)
+	if err != nil {
+		b.Fatalf("Invalid -bench.sizes: %s", err)
+	}
+
+	for _, p := range payloads {
+		for _, printable := range []bool{Printable, Binary} {
+			kind := "Binary"
+			if printable {
+				kind = "Printable"
+			}
+			msg := randString(p.rows, p.cols, printable)
+			name := fmt.Sprintf("%dx%d%s&Clients=%d", p.rows, p.cols, kind, *benchClients)
+			b.Run(name, func(b *testing.B) { benchTransfer(b, *benchClients, msg, printable) })
+		}
+	}
+}
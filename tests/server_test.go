@@ -32,7 +32,7 @@
  // Start() starts the server so it is open for accepting connections.
  // If an error occurs while starting the server, fail the provided test,
  // and return the error.
- func (srv *Server) Start(t *testing.T) error {
+ func (srv *Server) Start(t testing.TB) error {
 	 // Create a command to run the student server executable
 	 server := filepath.Join(solutionDir, "server")
 	 debug.Println("Executable:", server)
@@ -107,7 +107,7 @@
  // after it has been stopped - just make a new one.
  // If an error occurs while stopping the server fail the provided test and
  // return the error. (TODO: Is it a good idea to fail tests for this?)
- func (srv *Server) Stop(t *testing.T) error {
+ func (srv *Server) Stop(t testing.TB) error {
 	 debug.Println("Stopping server... ")
  
 	 if !srv.alive {
@@ -80,6 +80,9 @@ func TestMain(m *testing.M) {
 	}
 
 	m.Run()
+
+	// Emit results of any benchmarks that ran (see bench_test.go)
+	writeBenchResults()
 }
 
 /******************************************************************************/